@@ -0,0 +1,441 @@
+// Command mdasio-gen generates typed Read<T>/Write<T> functions for Go
+// structs tagged with `mdas:"name,order=N,optional"` struct tags, so
+// callers stop hand-writing the six-line WriteX/ReadX chains WriteGrid
+// already shows the risk of (a single misplaced read was the cause of
+// the historical ReadGrid row-decode bug).
+//
+// Usage:
+//
+//	mdasio-gen -input types.go [-output types_gen.go]
+//
+// Every struct in the input file, exported or not, that has at least one field
+// tagged `mdas:"..."` is treated as a schema: its tagged fields are
+// encoded in ascending `order=` (default: declaration order) as a
+// sequence of MdasIO primitive calls, nested struct calls, or
+// length-prefixed loops for slices. A field tagged `optional` is preceded
+// on the wire by a bool marking whether it was present.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+func main() {
+	input := flag.String("input", "", "Go source file to scan for mdas-tagged structs")
+	output := flag.String("output", "", "output file (default: <input>_gen.go)")
+	pkg := flag.String("package", "", "package name for the generated file (default: the input file's package)")
+	flag.Parse()
+
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "mdasio-gen: -input is required")
+		os.Exit(1)
+	}
+	outPath := *output
+	if outPath == "" {
+		outPath = strings.TrimSuffix(*input, ".go") + "_gen.go"
+	}
+
+	if err := run(*input, outPath, *pkg); err != nil {
+		fmt.Fprintln(os.Stderr, "mdasio-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(inputPath, outPath, pkgOverride string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, inputPath, nil, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", inputPath, err)
+	}
+
+	pkgName := file.Name.Name
+	if pkgOverride != "" {
+		pkgName = pkgOverride
+	}
+
+	schemas, err := collectSchemas(file)
+	if err != nil {
+		return err
+	}
+	if len(schemas) == 0 {
+		return fmt.Errorf("no mdas-tagged structs found in %s", inputPath)
+	}
+
+	// mdasio is referenced unqualified when generating inside package
+	// mdasio itself, and as mdasio.X otherwise (the generated file must
+	// import it under that name).
+	qualify := pkgName != "mdasio"
+	knownTypes := make(map[string]bool, len(schemas))
+	for _, s := range schemas {
+		knownTypes[s.Name] = true
+	}
+
+	src, err := renderSchemas(pkgName, qualify, schemas, knownTypes)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("format generated source: %w\n%s", err, src)
+	}
+
+	return os.WriteFile(outPath, formatted, 0o644)
+}
+
+// field is one mdas-tagged struct field, ready for code generation.
+type field struct {
+	GoName   string
+	WireName string
+	Order    int
+	Optional bool
+	TypeExpr ast.Expr
+}
+
+// schema is one struct type to generate Read<T>/Write<T> functions for.
+type schema struct {
+	Name   string
+	Fields []field
+}
+
+func collectSchemas(file *ast.File) ([]schema, error) {
+	var schemas []schema
+
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+
+			var fields []field
+			for _, f := range st.Fields.List {
+				if f.Tag == nil || len(f.Names) == 0 {
+					continue
+				}
+				tag, ok, err := parseTag(f.Tag.Value)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", ts.Name.Name, f.Names[0].Name, err)
+				}
+				if !ok {
+					continue
+				}
+				name := f.Names[0].Name
+				wireName := tag.name
+				if wireName == "" {
+					wireName = name
+				}
+				fields = append(fields, field{
+					GoName:   name,
+					WireName: wireName,
+					Order:    tag.order,
+					Optional: tag.optional,
+					TypeExpr: f.Type,
+				})
+			}
+			if len(fields) == 0 {
+				continue
+			}
+
+			sort.SliceStable(fields, func(i, j int) bool { return fields[i].Order < fields[j].Order })
+			schemas = append(schemas, schema{Name: ts.Name.Name, Fields: fields})
+		}
+	}
+
+	return schemas, nil
+}
+
+type mdasTag struct {
+	name     string
+	order    int
+	optional bool
+}
+
+// parseTag reads the `mdas:"name,order=N,optional"` tag out of a raw
+// struct tag literal (as found in ast.Field.Tag.Value, backticks and
+// all). ok is false if the field has no mdas tag.
+func parseTag(raw string) (mdasTag, bool, error) {
+	unquoted, err := strconv.Unquote(raw)
+	if err != nil {
+		return mdasTag{}, false, fmt.Errorf("invalid tag literal %s: %w", raw, err)
+	}
+	value, ok := reflect.StructTag(unquoted).Lookup("mdas")
+	if !ok {
+		return mdasTag{}, false, nil
+	}
+
+	parts := strings.Split(value, ",")
+	tag := mdasTag{name: parts[0]}
+	for _, p := range parts[1:] {
+		switch {
+		case p == "optional":
+			tag.optional = true
+		case strings.HasPrefix(p, "order="):
+			n, err := strconv.Atoi(strings.TrimPrefix(p, "order="))
+			if err != nil {
+				return mdasTag{}, false, fmt.Errorf("invalid order in tag %q: %w", value, err)
+			}
+			tag.order = n
+		}
+	}
+	return tag, true, nil
+}
+
+// codec names the MdasIO primitive (or nested Read<T>/Write<T> pair) used
+// to encode a field's base type, i.e. with any slice/pointer stripped.
+type codec struct {
+	readExpr  string // e.g. "m.ReadFloat()"
+	writeStmt string // e.g. "m.WriteFloat(%s)"
+	typeName  string // Go type as written in generated code
+}
+
+func builtinCodecs(qualify bool) map[string]codec {
+	prefix := ""
+	if qualify {
+		prefix = "mdasio."
+	}
+	return map[string]codec{
+		"string":        {"m.ReadString()", "m.WriteString(%s)", "string"},
+		"bool":          {"m.ReadBool()", "m.WriteBool(%s)", "bool"},
+		"int":           {"m.ReadInt()", "m.WriteInt(%s)", "int"},
+		"int16":         {"m.ReadInt16()", "m.WriteInt16(%s)", "int16"},
+		"int64":         {"m.ReadInt64()", "m.WriteInt64(%s)", "int64"},
+		"float32":       {"m.ReadFloat()", "m.WriteFloat(%s)", "float32"},
+		"time.Time":     {"m.ReadDate()", "m.WriteDate(%s)", "time.Time"},
+		"time.Duration": {"m.ReadDuration()", "m.WriteDuration(%s)", "time.Duration"},
+		"Point":         {"m.ReadPoint()", "m.WritePoint(%s)", prefix + "Point"},
+		"Grid":          {"m.ReadGrid()", "m.WriteGrid(%s)", prefix + "Grid"},
+		"Unit":          {"m.ReadUnit()", "m.WriteUnit(%s)", prefix + "Unit"},
+		"Geometry":      {"m.ReadGeometry()", "m.WriteGeometry(%s)", prefix + "Geometry"},
+	}
+}
+
+// typeKeys returns the lookup key(s) for expr that builtinCodecs/knownTypes
+// may recognize: the bare identifier for local types and builtins, the
+// bare "Name" for a selector into the mdasio package (whose builtins are
+// keyed by their bare name), or "pkg.Name" for any other selector such as
+// time.Time.
+func typeKeys(expr ast.Expr) []string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return []string{t.Name}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			if pkg.Name == "mdasio" {
+				return []string{t.Sel.Name}
+			}
+			return []string{pkg.Name + "." + t.Sel.Name}
+		}
+	}
+	return nil
+}
+
+// resolveCodec returns the codec for expr, treating any type in
+// knownTypes (the other schemas in this file) as a nested Read<T>/Write<T>
+// pair.
+func resolveCodec(expr ast.Expr, builtins map[string]codec, knownTypes map[string]bool) (codec, bool) {
+	for _, key := range typeKeys(expr) {
+		if c, ok := builtins[key]; ok {
+			return c, true
+		}
+		if knownTypes[key] {
+			return codec{
+				readExpr:  fmt.Sprintf("Read%s(m)", key),
+				writeStmt: fmt.Sprintf("Write%s(m, %%s)", key),
+				typeName:  key,
+			}, true
+		}
+	}
+	return codec{}, false
+}
+
+const schemaTemplate = `
+// Read{{.Name}} reads a {{.Name}} written by Write{{.Name}}.
+func Read{{.Name}}(m *{{.MdasIOType}}) ({{.TypeName}}, error) {
+	var v {{.TypeName}}
+	var err error
+{{range .Fields}}
+{{if .Optional}}	{ present, perr := m.ReadBool()
+		if perr != nil {
+			return v, perr
+		}
+		if present {
+			var fv {{.FieldTypeName}}
+			if fv, err = {{.ReadExpr}}; err != nil {
+				return v, err
+			}
+			v.{{.GoName}} = &fv
+		}
+	}
+{{else if .IsSlice}}	{ n, nerr := m.ReadInt()
+		if nerr != nil {
+			return v, nerr
+		}
+		v.{{.GoName}} = make({{.SliceTypeName}}, n)
+		for i := 0; i < n; i++ {
+			if v.{{.GoName}}[i], err = {{.ReadExpr}}; err != nil {
+				return v, err
+			}
+		}
+	}
+{{else}}	if v.{{.GoName}}, err = {{.ReadExpr}}; err != nil {
+		return v, err
+	}
+{{end}}{{end}}
+	return v, nil
+}
+
+// Write{{.Name}} writes v in the layout Read{{.Name}} expects.
+func Write{{.Name}}(m *{{.MdasIOType}}, v {{.TypeName}}) error {
+	var err error
+{{range .Fields}}
+{{if .Optional}}	if v.{{.GoName}} != nil {
+		if err = m.WriteBool(true); err != nil {
+			return err
+		}
+		if err = {{printf .WriteStmt (print "*v." .GoName)}}; err != nil {
+			return err
+		}
+	} else if err = m.WriteBool(false); err != nil {
+		return err
+	}
+{{else if .IsSlice}}	if err = m.WriteInt(len(v.{{.GoName}})); err != nil {
+		return err
+	}
+	for _, item := range v.{{.GoName}} {
+		if err = {{printf .WriteStmt "item"}}; err != nil {
+			return err
+		}
+	}
+{{else}}	if err = {{printf .WriteStmt (print "v." .GoName)}}; err != nil {
+		return err
+	}
+{{end}}{{end}}
+	return nil
+}
+`
+
+// renderField is the data passed to schemaTemplate for one field.
+type renderField struct {
+	GoName        string
+	Optional      bool
+	IsSlice       bool
+	ReadExpr      string
+	WriteStmt     string
+	FieldTypeName string
+	SliceTypeName string
+}
+
+// renderSchema is the data passed to schemaTemplate for one struct.
+type renderSchema struct {
+	Name       string
+	TypeName   string
+	MdasIOType string
+	Fields     []renderField
+}
+
+func renderSchemas(pkgName string, qualify bool, schemas []schema, knownTypes map[string]bool) ([]byte, error) {
+	builtins := builtinCodecs(qualify)
+
+	tmpl, err := template.New("schema").Parse(schemaTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	mdasIOType := "MdasIO"
+	if qualify {
+		mdasIOType = "mdasio.MdasIO"
+	}
+
+	needsTime := false
+	renderSchemas := make([]renderSchema, 0, len(schemas))
+	for _, s := range schemas {
+		// Every schema is a struct defined in the input file itself, so
+		// its type name is never qualified (only the mdasio builtins
+		// referenced by its fields are).
+		rs := renderSchema{Name: s.Name, TypeName: s.Name, MdasIOType: mdasIOType}
+		for _, f := range s.Fields {
+			elem := f.TypeExpr
+			isSlice := false
+			if at, ok := f.TypeExpr.(*ast.ArrayType); ok && at.Len == nil {
+				isSlice = true
+				elem = at.Elt
+			}
+			_, isPointer := elem.(*ast.StarExpr)
+			if st, ok := elem.(*ast.StarExpr); ok {
+				elem = st.X
+			}
+			if f.Optional && !isPointer {
+				return nil, fmt.Errorf("%s.%s: field tagged optional must have a pointer type", s.Name, f.GoName)
+			}
+			if isPointer && !f.Optional {
+				return nil, fmt.Errorf("%s.%s: pointer field must be tagged optional", s.Name, f.GoName)
+			}
+
+			c, ok := resolveCodec(elem, builtins, knownTypes)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s: unsupported field type", s.Name, f.GoName)
+			}
+			// The type name is only spelled out in generated code for
+			// optional (var fv T) and slice (make([]T, n)) fields; a
+			// plain field never names its type, so only those two cases
+			// need the "time" import.
+			if (f.Optional || isSlice) && strings.HasPrefix(c.typeName, "time.") {
+				needsTime = true
+			}
+
+			rf := renderField{
+				GoName:        f.GoName,
+				Optional:      f.Optional,
+				IsSlice:       isSlice,
+				ReadExpr:      c.readExpr,
+				WriteStmt:     c.writeStmt,
+				FieldTypeName: c.typeName,
+				SliceTypeName: "[]" + c.typeName,
+			}
+			rs.Fields = append(rs.Fields, rf)
+		}
+		renderSchemas = append(renderSchemas, rs)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by mdasio-gen. DO NOT EDIT.\n\npackage %s\n\n", pkgName)
+	if needsTime || qualify {
+		buf.WriteString("import (\n")
+		if needsTime {
+			buf.WriteString("\t\"time\"\n")
+		}
+		if qualify {
+			buf.WriteString("\n\t\"github.com/Miha3009/mdasio\"\n")
+		}
+		buf.WriteString(")\n")
+	}
+
+	for _, rs := range renderSchemas {
+		if err := tmpl.Execute(&buf, rs); err != nil {
+			return nil, fmt.Errorf("%s: %w", rs.Name, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunGeneratesValidSource(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "types.go")
+	output := filepath.Join(dir, "types_gen.go")
+
+	src := `package fixture
+
+type Widget struct {
+	Name string  ` + "`mdas:\"name,order=1\"`" + `
+	Note *string ` + "`mdas:\"note,order=2,optional\"`" + `
+}
+`
+	if err := os.WriteFile(input, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(input, output, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, output, nil, 0)
+	if err != nil {
+		t.Fatalf("generated file does not parse: %v", err)
+	}
+
+	wantFuncs := map[string]bool{"ReadWidget": false, "WriteWidget": false}
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if ok {
+			if _, ok := wantFuncs[fd.Name.Name]; ok {
+				wantFuncs[fd.Name.Name] = true
+			}
+		}
+	}
+	for name, found := range wantFuncs {
+		if !found {
+			t.Errorf("generated source missing func %s", name)
+		}
+	}
+}
+
+func TestRunRejectsOptionalNonPointerField(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "types.go")
+	output := filepath.Join(dir, "types_gen.go")
+
+	src := `package fixture
+
+type Bad struct {
+	Nick string ` + "`mdas:\"nick,order=1,optional\"`" + `
+}
+`
+	if err := os.WriteFile(input, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(input, output, ""); err == nil {
+		t.Fatal("expected run to reject an optional non-pointer field")
+	}
+}
+
+func TestRunRejectsUntaggedPointerField(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "types.go")
+	output := filepath.Join(dir, "types_gen.go")
+
+	src := `package fixture
+
+type Bad struct {
+	Nick *string ` + "`mdas:\"nick,order=1\"`" + `
+}
+`
+	if err := os.WriteFile(input, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run(input, output, ""); err == nil {
+		t.Fatal("expected run to reject a pointer field missing the optional tag")
+	}
+}
@@ -0,0 +1,32 @@
+package mdasio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressedGridRoundTrip(t *testing.T) {
+	g := NewGrid(GridHeader{N: 2, M: 2, NoData: -9999})
+	for i, v := range []float32{1, 2, 3, 4} {
+		g.Set(i/2, i%2, v)
+	}
+
+	var buf bytes.Buffer
+	m := NewMdasIO(nil, &buf)
+	if err := m.WriteCompressedGrid(g); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewMdasIO(&buf, nil).ReadCompressedGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GridHeader != g.GridHeader {
+		t.Fatalf("header mismatch: got %+v want %+v", got.GridHeader, g.GridHeader)
+	}
+	for i, want := range []float32{1, 2, 3, 4} {
+		if got.At(i/2, i%2) != want {
+			t.Fatalf("cell %d: got %v want %v", i, got.At(i/2, i%2), want)
+		}
+	}
+}
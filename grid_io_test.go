@@ -0,0 +1,94 @@
+package mdasio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGridFromIntoV1(t *testing.T) {
+	g := NewGrid(GridHeader{N: 1, M: 4})
+	for j, v := range []float32{1, 2, 3, 4} {
+		g.Set(0, j, v)
+	}
+
+	var buf bytes.Buffer
+	mw := NewMdasIO(nil, &buf)
+	if mw.version != Version1 {
+		t.Fatalf("NewMdasIO should default to Version1, got %d", mw.version)
+	}
+	if err := mw.WriteGridFrom(&g); err != nil {
+		t.Fatal(err)
+	}
+
+	// Written with the default (V1) layout, so it must also be readable
+	// through the row-at-a-time ReadGrid/ReadGridV1 path.
+	mr := NewMdasIO(&buf, nil)
+	got, err := mr.ReadGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j, want := range []float32{1, 2, 3, 4} {
+		if got.At(0, j) != want {
+			t.Fatalf("cell %d: got %v want %v", j, got.At(0, j), want)
+		}
+	}
+}
+
+func TestGridFromIntoV2(t *testing.T) {
+	g := NewGrid(GridHeader{N: 1, M: 2, NoData: -1})
+	g.Set(0, 0, 10)
+	g.Set(0, 1, 20)
+
+	var buf bytes.Buffer
+	mw, err := NewMdasIOWriter(&buf, Version2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.WriteGridFrom(&g); err != nil {
+		t.Fatal(err)
+	}
+
+	mr, err := NewMdasIOReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Grid
+	if err := mr.ReadGridInto(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.NoData != -1 {
+		t.Fatalf("NoData: got %v want -1", got.NoData)
+	}
+	if got.At(0, 0) != 10 || got.At(0, 1) != 20 {
+		t.Fatalf("cells: got [%v %v] want [10 20]", got.At(0, 0), got.At(0, 1))
+	}
+}
+
+func TestGridReaderWriterRoundTrip(t *testing.T) {
+	g := NewGrid(GridHeader{N: 2, M: 2})
+	for i := range g.data {
+		g.data[i] = float32(i)
+	}
+
+	var buf bytes.Buffer
+	n, err := g.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo returned %d, buffer holds %d bytes", n, buf.Len())
+	}
+
+	var got Grid
+	if _, err := got.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got.GridHeader != g.GridHeader {
+		t.Fatalf("header mismatch: got %+v want %+v", got.GridHeader, g.GridHeader)
+	}
+	for i := range g.data {
+		if got.data[i] != g.data[i] {
+			t.Fatalf("cell %d: got %v want %v", i, got.data[i], g.data[i])
+		}
+	}
+}
@@ -32,24 +32,53 @@ type GridHeader struct {
 	MaxLon  float32
 	StepLat float32
 	StepLon float32
+	NoData  float32 // V2 only: sentinel value marking missing cells
 }
 
+// Grid holds N*M float32 cells in a single contiguous, row-major backing
+// store. Use NewGrid to allocate one and Row/At/Set to access cells; this
+// avoids the N per-row allocations a [][]float32 would need.
 type Grid struct {
 	GridHeader
-	Data [][]float32
+	data []float32
+}
+
+// NewGrid allocates a Grid for h with a zeroed N*M backing store.
+func NewGrid(h GridHeader) Grid {
+	return Grid{GridHeader: h, data: make([]float32, h.N*h.M)}
+}
+
+// Row returns the i-th row as a slice sharing g's backing store.
+func (g *Grid) Row(i int) []float32 {
+	return g.data[i*g.M : i*g.M+g.M]
+}
+
+// At returns the cell at row i, column j.
+func (g *Grid) At(i, j int) float32 {
+	return g.data[i*g.M+j]
+}
+
+// Set stores v in the cell at row i, column j.
+func (g *Grid) Set(i, j int, v float32) {
+	g.data[i*g.M+j] = v
 }
 
 type MdasIO struct {
-	r   io.Reader
-	w   io.Writer
-	buf []byte
+	r       io.Reader
+	w       io.Writer
+	buf     []byte
+	version uint16
 }
 
+// NewMdasIO builds an MdasIO over a raw, unversioned stream and assumes the
+// V1 layout. Use NewMdasIOReader/NewMdasIOWriter for streams that carry a
+// version header.
 func NewMdasIO(r io.Reader, w io.Writer) *MdasIO {
 	return &MdasIO{
-		r:   r,
-		w:   w,
-		buf: make([]byte, 8),
+		r:       r,
+		w:       w,
+		buf:     make([]byte, 8),
+		version: Version1,
 	}
 }
 
@@ -134,7 +163,20 @@ func (m *MdasIO) ReadGeometry() (Geometry, error) {
 	return Geometry(s), err
 }
 
+// ReadGrid reads a Grid using the codec selected by the stream's version
+// header (see NewMdasIOReader).
 func (m *MdasIO) ReadGrid() (Grid, error) {
+	switch m.version {
+	case Version2:
+		return m.ReadGridV2()
+	default:
+		return m.ReadGridV1()
+	}
+}
+
+// ReadGridV1 reads the original (V1) Grid layout: a GridHeader without
+// NoData, followed by N*M raw float32 cells.
+func (m *MdasIO) ReadGridV1() (Grid, error) {
 	var grid Grid
 	var err error
 
@@ -156,22 +198,35 @@ func (m *MdasIO) ReadGrid() (Grid, error) {
 		return grid, err
 	}
 
+	grid.data = make([]float32, grid.N*grid.M)
 	rowBuf := make([]byte, 4*grid.M)
-	grid.Data = make([][]float32, grid.N)
 	for i := 0; i < grid.N; i++ {
 		if _, err := io.ReadFull(m.r, rowBuf); err != nil {
 			return grid, err
 		}
-		grid.Data[i] = make([]float32, grid.M)
+		row := grid.Row(i)
 		for j := 0; j < grid.M; j++ {
-			grid.Data[i][j] = math.Float32frombits(binary.LittleEndian.Uint32(m.buf[4*j : 4*j+4]))
+			row[j] = math.Float32frombits(binary.LittleEndian.Uint32(rowBuf[4*j : 4*j+4]))
 		}
 	}
 
 	return grid, nil
 }
 
+// ReadUnit reads a Unit using the codec selected by the stream's version
+// header (see NewMdasIOReader).
 func (m *MdasIO) ReadUnit() (Unit, error) {
+	switch m.version {
+	case Version2:
+		return m.ReadUnitV2()
+	default:
+		return m.ReadUnitV1()
+	}
+}
+
+// ReadUnitV1 reads the original (V1) Unit layout: three length-prefixed
+// strings, Id/Name/Type in that order.
+func (m *MdasIO) ReadUnitV1() (Unit, error) {
 	var unit Unit
 	var err error
 
@@ -280,7 +335,43 @@ func (m *MdasIO) WriteGeometry(v Geometry) error {
 	return m.WriteString(string(v))
 }
 
+// WriteUnit writes a Unit using the codec the MdasIO was constructed with
+// (see NewMdasIOWriter).
+func (m *MdasIO) WriteUnit(v Unit) error {
+	switch m.version {
+	case Version2:
+		return m.WriteUnitV2(v)
+	default:
+		return m.WriteUnitV1(v)
+	}
+}
+
+// WriteUnitV1 writes the original (V1) Unit layout: three length-prefixed
+// strings, Id/Name/Type in that order.
+func (m *MdasIO) WriteUnitV1(v Unit) error {
+	if err := m.WriteString(v.Id); err != nil {
+		return err
+	}
+	if err := m.WriteString(v.Name); err != nil {
+		return err
+	}
+	return m.WriteString(v.Type)
+}
+
+// WriteGrid writes a Grid using the codec the MdasIO was constructed with
+// (see NewMdasIOWriter).
 func (m *MdasIO) WriteGrid(v Grid) error {
+	switch m.version {
+	case Version2:
+		return m.WriteGridV2(v)
+	default:
+		return m.WriteGridV1(v)
+	}
+}
+
+// WriteGridV1 writes the original (V1) Grid layout: a GridHeader without
+// NoData, followed by N*M raw float32 cells.
+func (m *MdasIO) WriteGridV1(v Grid) error {
 	if err := m.WriteInt(v.N); err != nil {
 		return err
 	} else if err := m.WriteInt(v.M); err != nil {
@@ -301,8 +392,9 @@ func (m *MdasIO) WriteGrid(v Grid) error {
 
 	rowBuf := make([]byte, 4*v.M)
 	for i := 0; i < v.N; i++ {
+		row := v.Row(i)
 		for j := 0; j < v.M; j++ {
-			binary.LittleEndian.PutUint32(rowBuf[4*j:4*j+4], math.Float32bits(v.Data[i][j]))
+			binary.LittleEndian.PutUint32(rowBuf[4*j:4*j+4], math.Float32bits(row[j]))
 		}
 		if err := m.writeAll(rowBuf); err != nil {
 			return err
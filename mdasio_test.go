@@ -0,0 +1,102 @@
+package mdasio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGridV1RoundTrip(t *testing.T) {
+	g := NewGrid(GridHeader{N: 2, M: 3, MinLat: 1, MaxLat: 2, MinLon: 3, MaxLon: 4, StepLat: 0.5, StepLon: 0.25})
+	vals := []float32{1, 2, 3, 4, 5, 6}
+	for i, v := range vals {
+		g.Set(i/3, i%3, v)
+	}
+
+	var buf bytes.Buffer
+	if err := NewMdasIO(nil, &buf).WriteGrid(g); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewMdasIO(&buf, nil).ReadGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GridHeader != g.GridHeader {
+		t.Fatalf("header mismatch: got %+v want %+v", got.GridHeader, g.GridHeader)
+	}
+	for i, v := range vals {
+		if got.At(i/3, i%3) != v {
+			t.Fatalf("cell %d: got %v want %v", i, got.At(i/3, i%3), v)
+		}
+	}
+}
+
+func TestGridV2RoundTrip(t *testing.T) {
+	g := NewGrid(GridHeader{N: 1, M: 2, NoData: -9999})
+	g.Set(0, 0, 1)
+	g.Set(0, 1, 2)
+
+	var buf bytes.Buffer
+	mw, err := NewMdasIOWriter(&buf, Version2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.WriteGrid(g); err != nil {
+		t.Fatal(err)
+	}
+
+	mr, err := NewMdasIOReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := mr.ReadGrid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NoData != -9999 {
+		t.Fatalf("NoData: got %v want -9999", got.NoData)
+	}
+	if got.At(0, 0) != 1 || got.At(0, 1) != 2 {
+		t.Fatalf("cells: got [%v %v] want [1 2]", got.At(0, 0), got.At(0, 1))
+	}
+}
+
+func TestUnitV1RoundTrip(t *testing.T) {
+	u := Unit{Id: "u1", Name: "Unit One", Type: "kind"}
+
+	var buf bytes.Buffer
+	if err := NewMdasIO(nil, &buf).WriteUnit(u); err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewMdasIO(&buf, nil).ReadUnit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != u {
+		t.Fatalf("got %+v want %+v", got, u)
+	}
+}
+
+func TestUnitV2RoundTrip(t *testing.T) {
+	u := Unit{Id: "u2", Name: "Unit Two", Type: "kind"}
+
+	var buf bytes.Buffer
+	mw, err := NewMdasIOWriter(&buf, Version2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.WriteUnit(u); err != nil {
+		t.Fatal(err)
+	}
+	mr, err := NewMdasIOReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := mr.ReadUnit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != u {
+		t.Fatalf("got %+v want %+v", got, u)
+	}
+}
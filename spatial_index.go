@@ -0,0 +1,274 @@
+package mdasio
+
+import (
+	"io"
+	"math"
+	"sort"
+)
+
+// BBox is an axis-aligned lat/lon bounding box, as used by SpatialIndex.
+type BBox struct {
+	MinLat float32
+	MinLon float32
+	MaxLat float32
+	MaxLon float32
+}
+
+// Intersects reports whether b and o overlap, including touching edges.
+func (b BBox) Intersects(o BBox) bool {
+	return b.MinLat <= o.MaxLat && b.MaxLat >= o.MinLat &&
+		b.MinLon <= o.MaxLon && b.MaxLon >= o.MinLon
+}
+
+func (b BBox) union(o BBox) BBox {
+	return BBox{
+		MinLat: float32(math.Min(float64(b.MinLat), float64(o.MinLat))),
+		MinLon: float32(math.Min(float64(b.MinLon), float64(o.MinLon))),
+		MaxLat: float32(math.Max(float64(b.MaxLat), float64(o.MaxLat))),
+		MaxLon: float32(math.Max(float64(b.MaxLon), float64(o.MaxLon))),
+	}
+}
+
+// BBoxFromPoint returns the degenerate bbox covering a single Point.
+func BBoxFromPoint(p Point) BBox {
+	return BBox{MinLat: p.Lat, MaxLat: p.Lat, MinLon: p.Lon, MaxLon: p.Lon}
+}
+
+// BBoxFromGridHeader returns the bbox a GridHeader covers.
+func BBoxFromGridHeader(h GridHeader) BBox {
+	return BBox{MinLat: h.MinLat, MaxLat: h.MaxLat, MinLon: h.MinLon, MaxLon: h.MaxLon}
+}
+
+// spatialIndexNode is one node of a SpatialIndex, stored in preorder.
+// Leaf nodes carry a payload index into the caller's original slice;
+// internal nodes carry indices of their children within the same node
+// slice.
+type spatialIndexNode struct {
+	bbox     BBox
+	isLeaf   bool
+	leaf     int
+	children []int
+}
+
+// SpatialIndex is a Sort-Tile-Recursive R-tree over a set of bounding
+// boxes, bulk-loaded once and queried by bbox intersection. It is built
+// with NewSpatialIndex and can be serialized with
+// MdasIO.WriteSpatialIndex / MdasIO.ReadSpatialIndex.
+type SpatialIndex struct {
+	fanout int
+	nodes  []spatialIndexNode // preorder, root at index 0
+}
+
+// NewSpatialIndex bulk-loads boxes (typically built with BBoxFromPoint or
+// BBoxFromGridHeader) into an STR R-tree with at most fanout entries per
+// node. fanout must be in [2, 255]. Query results are indices into boxes.
+func NewSpatialIndex(boxes []BBox, fanout int) *SpatialIndex {
+	idx := &SpatialIndex{fanout: fanout}
+	if len(boxes) == 0 {
+		return idx
+	}
+
+	level := make([]*strNode, len(boxes))
+	for i, b := range boxes {
+		level[i] = &strNode{bbox: b, isLeaf: true, leaf: i}
+	}
+	for len(level) > 1 {
+		level = strTile(level, fanout)
+	}
+
+	idx.nodes = make([]spatialIndexNode, 0, len(level))
+	flattenSTR(&idx.nodes, level[0])
+	return idx
+}
+
+// strNode is the intermediate, pointer-based tree NewSpatialIndex builds
+// before flattening it into SpatialIndex.nodes preorder.
+type strNode struct {
+	bbox     BBox
+	isLeaf   bool
+	leaf     int
+	children []*strNode
+}
+
+// strTile groups nodes into STR tiles of at most fanout entries and
+// returns the parent nodes for the next level up.
+func strTile(nodes []*strNode, fanout int) []*strNode {
+	n := len(nodes)
+	leafGroups := (n + fanout - 1) / fanout
+	slices := int(math.Ceil(math.Sqrt(float64(leafGroups))))
+	if slices < 1 {
+		slices = 1
+	}
+	perSlice := (n + slices - 1) / slices
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return centerLon(nodes[i].bbox) < centerLon(nodes[j].bbox)
+	})
+
+	var parents []*strNode
+	for s := 0; s < n; s += perSlice {
+		end := s + perSlice
+		if end > n {
+			end = n
+		}
+		slice := nodes[s:end]
+		sort.Slice(slice, func(i, j int) bool {
+			return centerLat(slice[i].bbox) < centerLat(slice[j].bbox)
+		})
+		for g := 0; g < len(slice); g += fanout {
+			gEnd := g + fanout
+			if gEnd > len(slice) {
+				gEnd = len(slice)
+			}
+			group := slice[g:gEnd]
+			parents = append(parents, groupNode(group))
+		}
+	}
+	return parents
+}
+
+func groupNode(group []*strNode) *strNode {
+	bbox := group[0].bbox
+	for _, c := range group[1:] {
+		bbox = bbox.union(c.bbox)
+	}
+	return &strNode{bbox: bbox, children: append([]*strNode(nil), group...)}
+}
+
+func centerLat(b BBox) float32 { return (b.MinLat + b.MaxLat) / 2 }
+func centerLon(b BBox) float32 { return (b.MinLon + b.MaxLon) / 2 }
+
+// flattenSTR appends t and its subtree to nodes in preorder, returning
+// t's index in nodes.
+func flattenSTR(nodes *[]spatialIndexNode, t *strNode) int {
+	i := len(*nodes)
+	*nodes = append(*nodes, spatialIndexNode{bbox: t.bbox, isLeaf: t.isLeaf, leaf: t.leaf})
+	if !t.isLeaf {
+		children := make([]int, len(t.children))
+		for k, c := range t.children {
+			children[k] = flattenSTR(nodes, c)
+		}
+		(*nodes)[i].children = children
+	}
+	return i
+}
+
+// Query returns the indices (into the slice NewSpatialIndex was built
+// from) of every box that intersects q.
+func (idx *SpatialIndex) Query(q BBox) []int {
+	if len(idx.nodes) == 0 {
+		return nil
+	}
+	var result []int
+	idx.queryNode(0, q, &result)
+	return result
+}
+
+func (idx *SpatialIndex) queryNode(i int, q BBox, result *[]int) {
+	n := &idx.nodes[i]
+	if !n.bbox.Intersects(q) {
+		return
+	}
+	if n.isLeaf {
+		*result = append(*result, n.leaf)
+		return
+	}
+	for _, c := range n.children {
+		idx.queryNode(c, q, result)
+	}
+}
+
+// WriteSpatialIndex writes idx as: a uint8 entries-per-node, the node
+// count, then a preorder traversal of nodes. Each node carries its bbox,
+// an is-leaf flag, and either its payload index (leaf) or its child
+// indices (internal).
+func (m *MdasIO) WriteSpatialIndex(idx *SpatialIndex) error {
+	if err := m.writeAll([]byte{byte(idx.fanout)}); err != nil {
+		return err
+	}
+	if err := m.WriteInt(len(idx.nodes)); err != nil {
+		return err
+	}
+
+	for _, n := range idx.nodes {
+		if err := m.WriteFloat(n.bbox.MinLat); err != nil {
+			return err
+		} else if err := m.WriteFloat(n.bbox.MinLon); err != nil {
+			return err
+		} else if err := m.WriteFloat(n.bbox.MaxLat); err != nil {
+			return err
+		} else if err := m.WriteFloat(n.bbox.MaxLon); err != nil {
+			return err
+		} else if err := m.WriteBool(n.isLeaf); err != nil {
+			return err
+		}
+
+		if n.isLeaf {
+			if err := m.WriteInt(n.leaf); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := m.WriteInt(len(n.children)); err != nil {
+			return err
+		}
+		for _, c := range n.children {
+			if err := m.WriteInt(c); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReadSpatialIndex reads a SpatialIndex written by WriteSpatialIndex.
+func (m *MdasIO) ReadSpatialIndex() (*SpatialIndex, error) {
+	fanoutByte := make([]byte, 1)
+	if _, err := io.ReadFull(m.r, fanoutByte); err != nil {
+		return nil, err
+	}
+	idx := &SpatialIndex{fanout: int(fanoutByte[0])}
+
+	nodeCount, err := m.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	idx.nodes = make([]spatialIndexNode, nodeCount)
+
+	for i := 0; i < nodeCount; i++ {
+		var n spatialIndexNode
+		if n.bbox.MinLat, err = m.ReadFloat(); err != nil {
+			return nil, err
+		} else if n.bbox.MinLon, err = m.ReadFloat(); err != nil {
+			return nil, err
+		} else if n.bbox.MaxLat, err = m.ReadFloat(); err != nil {
+			return nil, err
+		} else if n.bbox.MaxLon, err = m.ReadFloat(); err != nil {
+			return nil, err
+		} else if n.isLeaf, err = m.ReadBool(); err != nil {
+			return nil, err
+		}
+
+		if n.isLeaf {
+			if n.leaf, err = m.ReadInt(); err != nil {
+				return nil, err
+			}
+		} else {
+			childCount, err := m.ReadInt()
+			if err != nil {
+				return nil, err
+			}
+			n.children = make([]int, childCount)
+			for k := range n.children {
+				if n.children[k], err = m.ReadInt(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		idx.nodes[i] = n
+	}
+
+	return idx, nil
+}
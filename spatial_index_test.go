@@ -0,0 +1,62 @@
+package mdasio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSpatialIndexQuery(t *testing.T) {
+	boxes := []BBox{
+		BBoxFromPoint(Point{Lat: 0, Lon: 0}),
+		BBoxFromPoint(Point{Lat: 10, Lon: 10}),
+		BBoxFromPoint(Point{Lat: -10, Lon: -10}),
+	}
+	idx := NewSpatialIndex(boxes, 2)
+
+	got := idx.Query(BBox{MinLat: -1, MaxLat: 1, MinLon: -1, MaxLon: 1})
+	if len(got) != 1 || got[0] != 0 {
+		t.Fatalf("got %v, want [0]", got)
+	}
+
+	all := idx.Query(BBox{MinLat: -100, MaxLat: 100, MinLon: -100, MaxLon: 100})
+	if len(all) != 3 {
+		t.Fatalf("got %v, want all 3 indices", all)
+	}
+}
+
+func TestSpatialIndexRoundTrip(t *testing.T) {
+	boxes := []BBox{
+		BBoxFromPoint(Point{Lat: 1, Lon: 1}),
+		BBoxFromPoint(Point{Lat: 2, Lon: 2}),
+		BBoxFromPoint(Point{Lat: 3, Lon: 3}),
+		BBoxFromPoint(Point{Lat: 4, Lon: 4}),
+		BBoxFromPoint(Point{Lat: 5, Lon: 5}),
+	}
+	idx := NewSpatialIndex(boxes, 2)
+
+	var buf bytes.Buffer
+	if err := NewMdasIO(nil, &buf).WriteSpatialIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewMdasIO(&buf, nil).ReadSpatialIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := BBox{MinLat: -100, MaxLat: 100, MinLon: -100, MaxLon: 100}
+	want := idx.Query(q)
+	gotResult := got.Query(q)
+	if len(gotResult) != len(want) {
+		t.Fatalf("got %v, want %v", gotResult, want)
+	}
+	seen := make(map[int]bool)
+	for _, i := range gotResult {
+		seen[i] = true
+	}
+	for _, i := range want {
+		if !seen[i] {
+			t.Fatalf("missing index %d in round-tripped query result %v", i, gotResult)
+		}
+	}
+}
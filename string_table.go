@@ -0,0 +1,256 @@
+package mdasio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// stringTableBlockSize is the number of strings grouped into a single
+// length-class block when a StringTable is serialized.
+const stringTableBlockSize = 128
+
+// StringTable interns repeated strings (Unit ids, WKT geometries, ...) so
+// they can be written once and referenced by index. It buffers strings in
+// insertion order and writes them with WriteStringTable using a per-block
+// width class for the length prefixes, instead of a flat int32 length per
+// string.
+type StringTable struct {
+	strings []string
+	index   map[string]int
+}
+
+// NewStringTable returns an empty StringTable ready for interning.
+func NewStringTable() *StringTable {
+	return &StringTable{index: make(map[string]int)}
+}
+
+// Intern returns s's index in the table, adding it if it hasn't been seen
+// before.
+func (t *StringTable) Intern(s string) int {
+	if i, ok := t.index[s]; ok {
+		return i
+	}
+	i := len(t.strings)
+	t.strings = append(t.strings, s)
+	t.index[s] = i
+	return i
+}
+
+// Get returns the string stored at index i.
+func (t *StringTable) Get(i int) string {
+	return t.strings[i]
+}
+
+// Len returns the number of distinct strings interned so far.
+func (t *StringTable) Len() int {
+	return len(t.strings)
+}
+
+// stringTableWidthClass returns the narrowest byte width (1, 2 or 4) that
+// can hold maxLen as an unsigned integer.
+func stringTableWidthClass(maxLen int) byte {
+	switch {
+	case maxLen <= 0xff:
+		return 1
+	case maxLen <= 0xffff:
+		return 2
+	default:
+		return 4
+	}
+}
+
+func putStringLen(buf []byte, width byte, n int) {
+	switch width {
+	case 1:
+		buf[0] = byte(n)
+	case 2:
+		binary.LittleEndian.PutUint16(buf, uint16(n))
+	default:
+		binary.LittleEndian.PutUint32(buf, uint32(n))
+	}
+}
+
+func getStringLen(buf []byte, width byte) int {
+	switch width {
+	case 1:
+		return int(buf[0])
+	case 2:
+		return int(binary.LittleEndian.Uint16(buf))
+	default:
+		return int(binary.LittleEndian.Uint32(buf))
+	}
+}
+
+// WriteStringTable writes t as: total string count, block count, one
+// width-class byte per block, then for each block its packed lengths
+// ([width_class]-byte each) followed by the concatenated string bytes.
+func (m *MdasIO) WriteStringTable(t *StringTable) error {
+	total := len(t.strings)
+	blockCount := (total + stringTableBlockSize - 1) / stringTableBlockSize
+
+	if err := m.WriteInt(total); err != nil {
+		return err
+	}
+	if err := m.WriteInt(blockCount); err != nil {
+		return err
+	}
+
+	widths := make([]byte, blockCount)
+	for b := 0; b < blockCount; b++ {
+		block := t.blockAt(b)
+		maxLen := 0
+		for _, s := range block {
+			if len(s) > maxLen {
+				maxLen = len(s)
+			}
+		}
+		widths[b] = stringTableWidthClass(maxLen)
+	}
+	if err := m.writeAll(widths); err != nil {
+		return err
+	}
+
+	for b := 0; b < blockCount; b++ {
+		block := t.blockAt(b)
+		width := widths[b]
+
+		lenBuf := make([]byte, width)
+		for _, s := range block {
+			putStringLen(lenBuf, width, len(s))
+			if err := m.writeAll(lenBuf); err != nil {
+				return err
+			}
+		}
+		for _, s := range block {
+			if err := m.writeAll([]byte(s)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// blockAt returns the b-th block of stringTableBlockSize strings.
+func (t *StringTable) blockAt(b int) []string {
+	start := b * stringTableBlockSize
+	end := start + stringTableBlockSize
+	if end > len(t.strings) {
+		end = len(t.strings)
+	}
+	return t.strings[start:end]
+}
+
+// ReadStringTable reads a StringTable written by WriteStringTable.
+func (m *MdasIO) ReadStringTable() (*StringTable, error) {
+	total, err := m.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+	blockCount, err := m.ReadInt()
+	if err != nil {
+		return nil, err
+	}
+
+	widths := make([]byte, blockCount)
+	if _, err := io.ReadFull(m.r, widths); err != nil {
+		return nil, err
+	}
+
+	t := &StringTable{
+		strings: make([]string, 0, total),
+		index:   make(map[string]int, total),
+	}
+
+	remaining := total
+	for b := 0; b < blockCount; b++ {
+		width := widths[b]
+		count := stringTableBlockSize
+		if remaining < count {
+			count = remaining
+		}
+		remaining -= count
+
+		lens := make([]int, count)
+		lenBuf := make([]byte, width)
+		for i := 0; i < count; i++ {
+			if _, err := io.ReadFull(m.r, lenBuf); err != nil {
+				return nil, err
+			}
+			lens[i] = getStringLen(lenBuf, width)
+		}
+		for i := 0; i < count; i++ {
+			strBuf := make([]byte, lens[i])
+			if _, err := io.ReadFull(m.r, strBuf); err != nil {
+				return nil, err
+			}
+			s := string(strBuf)
+			t.index[s] = len(t.strings)
+			t.strings = append(t.strings, s)
+		}
+	}
+
+	return t, nil
+}
+
+// WriteVarint writes v as a little-endian base-128 varint.
+func (m *MdasIO) WriteVarint(v uint64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+	return m.writeAll(buf[:n])
+}
+
+// ReadVarint reads a little-endian base-128 varint written by WriteVarint.
+func (m *MdasIO) ReadVarint() (uint64, error) {
+	var v uint64
+	var shift uint
+	one := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(m.r, one); err != nil {
+			return 0, err
+		}
+		v |= uint64(one[0]&0x7f) << shift
+		if one[0] < 0x80 {
+			return v, nil
+		}
+		shift += 7
+	}
+}
+
+// WriteUnitInterned writes u.Id/Name/Type as varint indices into t instead
+// of raw length-prefixed strings. t must be written with WriteStringTable
+// (typically once, after all units have been interned) and read back with
+// ReadStringTable before ReadUnitInterned is used.
+func (m *MdasIO) WriteUnitInterned(u Unit, t *StringTable) error {
+	if err := m.WriteVarint(uint64(t.Intern(u.Id))); err != nil {
+		return err
+	}
+	if err := m.WriteVarint(uint64(t.Intern(u.Name))); err != nil {
+		return err
+	}
+	return m.WriteVarint(uint64(t.Intern(u.Type)))
+}
+
+// ReadUnitInterned reads a Unit written by WriteUnitInterned, resolving
+// its fields against t.
+func (m *MdasIO) ReadUnitInterned(t *StringTable) (Unit, error) {
+	var u Unit
+
+	id, err := m.ReadVarint()
+	if err != nil {
+		return u, err
+	}
+	name, err := m.ReadVarint()
+	if err != nil {
+		return u, err
+	}
+	typ, err := m.ReadVarint()
+	if err != nil {
+		return u, err
+	}
+
+	u.Id = t.Get(int(id))
+	u.Name = t.Get(int(name))
+	u.Type = t.Get(int(typ))
+	return u, nil
+}
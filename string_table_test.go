@@ -0,0 +1,77 @@
+package mdasio
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestUnitInternedRoundTrip(t *testing.T) {
+	units := []Unit{
+		{Id: "u1", Name: "Unit One", Type: "kind-a"},
+		{Id: "u2", Name: "Unit Two", Type: "kind-a"},
+		{Id: "u1", Name: "Unit One", Type: "kind-a"},
+	}
+
+	writeTable := NewStringTable()
+	var unitsBuf bytes.Buffer
+	m := NewMdasIO(nil, &unitsBuf)
+	for _, u := range units {
+		if err := m.WriteUnitInterned(u, writeTable); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if writeTable.Len() != 5 {
+		t.Fatalf("expected 5 distinct interned strings, got %d", writeTable.Len())
+	}
+
+	var tableBuf bytes.Buffer
+	if err := NewMdasIO(nil, &tableBuf).WriteStringTable(writeTable); err != nil {
+		t.Fatal(err)
+	}
+
+	readTable, err := NewMdasIO(&tableBuf, nil).ReadStringTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readTable.Len() != writeTable.Len() {
+		t.Fatalf("readTable.Len() = %d, want %d", readTable.Len(), writeTable.Len())
+	}
+
+	mr := NewMdasIO(&unitsBuf, nil)
+	for i, want := range units {
+		got, err := mr.ReadUnitInterned(readTable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("unit %d: got %+v want %+v", i, got, want)
+		}
+	}
+}
+
+func TestStringTableManyBlocks(t *testing.T) {
+	table := NewStringTable()
+	const n = stringTableBlockSize*2 + 7
+	for i := 0; i < n; i++ {
+		table.Intern(strconv.Itoa(i))
+	}
+
+	var buf bytes.Buffer
+	if err := NewMdasIO(nil, &buf).WriteStringTable(table); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewMdasIO(&buf, nil).ReadStringTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Len() != table.Len() {
+		t.Fatalf("got.Len() = %d, want %d", got.Len(), table.Len())
+	}
+	for i := 0; i < table.Len(); i++ {
+		if got.Get(i) != table.Get(i) {
+			t.Fatalf("entry %d: got %q want %q", i, got.Get(i), table.Get(i))
+		}
+	}
+}